@@ -0,0 +1,78 @@
+package config
+
+import "time"
+
+// BulkIndexingConfig controls how documents are batched before being sent to Elasticsearch
+type BulkIndexingConfig struct {
+	// Workers is the number of concurrent bulk commit workers
+	Workers int `yaml:"workers"`
+	// BulkActions is the number of documents that triggers a flush. Only honored by the
+	// api_version: 7 backend: the v8 client's BulkIndexer only flushes on byte size or
+	// FlushInterval, so this field has no effect when api_version is 8.
+	BulkActions int `yaml:"bulk_actions"`
+	// BulkSize is the accumulated request body size (in bytes) that triggers a flush
+	BulkSize int `yaml:"bulk_size"`
+	// FlushInterval is the maximum time to wait before flushing a partially filled batch
+	FlushInterval time.Duration `yaml:"flush_interval"`
+}
+
+// TLSConfig describes the certificates used to secure the connection to Elasticsearch
+type TLSConfig struct {
+	// CAFile is a PEM-encoded CA bundle used to verify the server certificate
+	CAFile string `yaml:"ca_file"`
+	// CertFile/KeyFile are a PEM-encoded client certificate and key, used for mTLS
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// InsecureSkipVerify disables server certificate verification; only use for testing
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+}
+
+// ElasticsearchConfig describes the Elasticsearch connection settings
+type ElasticsearchConfig struct {
+	Endpoints []string `yaml:"endpoints"`
+	Username  string   `yaml:"username"`
+	Password  string   `yaml:"password"`
+	Index     string   `yaml:"index"`
+
+	// APIVersion selects the client used to talk to the cluster: 7 (olivere/elastic,
+	// the default) or 8 (the official elastic/go-elasticsearch client, required for
+	// Elasticsearch 8/OpenSearch 2 clusters)
+	APIVersion int `yaml:"api_version"`
+
+	// TLS configures certificate-based and mTLS connections
+	TLS TLSConfig `yaml:"tls"`
+	// APIKey, if set, is sent as an "Authorization: ApiKey <APIKey>" header instead of
+	// basic auth
+	APIKey string `yaml:"api_key"`
+	// CloudID, if set, is decoded into the cluster endpoint for Elastic Cloud deployments
+	CloudID string `yaml:"cloud_id"`
+	// Gzip enables gzip compression of requests to the cluster
+	Gzip bool `yaml:"gzip"`
+	// HealthcheckInterval is how often the client itself pings the cluster for
+	// sniffing/healthchecks. On the api_version: 8 backend this is wired through as the
+	// v8 client's DiscoverNodesInterval, its closest equivalent.
+	HealthcheckInterval time.Duration `yaml:"healthcheck_interval"`
+	// SnifferTimeout bounds how long a sniffing round is allowed to take. Only honored by
+	// the api_version: 7 backend; the v8 client's connection pool has no equivalent knob.
+	SnifferTimeout time.Duration `yaml:"sniffer_timeout"`
+
+	// BulkIndexing configures the BulkProcessor used by StorageManager.Save
+	BulkIndexing BulkIndexingConfig `yaml:"bulk_indexing"`
+}
+
+// SQLStorageConfig describes the connection settings used by a SQL storage backend, e.g.
+// a Postgres DSN. Currently only consumed by the Postgres backend.
+type SQLStorageConfig struct {
+	// DataSourceName is a driver-specific connection string, e.g. a Postgres DSN
+	DataSourceName string `yaml:"data_source_name"`
+}
+
+// StorageConfig selects and configures the storage backend used by the API
+type StorageConfig struct {
+	// Driver is one of "elasticsearch" or "postgres". "sqlite" is reserved for a future
+	// backend and is rejected by manager.New until one is implemented.
+	Driver        string              `yaml:"driver"`
+	Elasticsearch ElasticsearchConfig `yaml:"elasticsearch"`
+	Postgres      SQLStorageConfig    `yaml:"postgres"`
+	SQLite        SQLStorageConfig    `yaml:"sqlite"`
+}
@@ -0,0 +1,39 @@
+package elasticsearch
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestDecodeCloudID(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte("elastic.co$abc123$def456"))
+
+	endpoint, err := decodeCloudID("my-deployment:" + encoded)
+	if err != nil {
+		t.Fatalf("decodeCloudID returned an error: %v", err)
+	}
+
+	want := "https://abc123.elastic.co"
+	if endpoint != want {
+		t.Errorf("decodeCloudID() = %q, want %q", endpoint, want)
+	}
+}
+
+func TestDecodeCloudIDMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		cloudID string
+	}{
+		{name: "missing colon separator", cloudID: "not-a-valid-cloud-id"},
+		{name: "not base64", cloudID: "my-deployment:not-base64!!!"},
+		{name: "missing dollar separators", cloudID: "my-deployment:" + base64.StdEncoding.EncodeToString([]byte("elastic.co"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCloudID(tt.cloudID); err == nil {
+				t.Errorf("decodeCloudID(%q) should have returned an error", tt.cloudID)
+			}
+		})
+	}
+}
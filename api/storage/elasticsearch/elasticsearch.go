@@ -2,25 +2,26 @@ package elasticsearch
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
 	"finala/api/config"
 	"finala/api/storage"
+	"fmt"
+	"io/ioutil"
+	"net/http"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	elastic "github.com/olivere/elastic/v7"
 	log "github.com/sirupsen/logrus"
 )
 
-// OrderedExecutionIDs will be the unmarshal response for ElasticSearch query  GetExecutions function
-type orderedExecutionIDs struct {
-	Buckets []struct {
-		Key string `json:"key"`
-	} `json:"buckets"`
-}
-
 const (
 	// indexMapping define the default index mapping
 	indexMapping = `{
@@ -40,81 +41,391 @@ const (
 	}`
 )
 
+const (
+	// defaultBulkWorkers is used when config.BulkIndexing.Workers is not set
+	defaultBulkWorkers = 1
+	// defaultBulkActions is used when config.BulkIndexing.BulkActions is not set
+	defaultBulkActions = 1000
+	// defaultBulkSize is used when config.BulkIndexing.BulkSize is not set
+	defaultBulkSize = 2 << 20 // 2MB
+	// defaultFlushInterval is used when config.BulkIndexing.FlushInterval is not set
+	defaultFlushInterval = 10 * time.Second
+)
+
+// availabilityCheckInterval is how often the cluster healthcheck goroutine pings Elasticsearch
+const availabilityCheckInterval = 10 * time.Second
+
+// defaultPageLimit is used for GetExecutions/GetResources when page.Limit is not set
+const defaultPageLimit = 100
+
 // StorageManager descrive elasticsearchStorage
 type StorageManager struct {
-	client       *elastic.Client
 	defaultIndex string
+
+	mu            sync.RWMutex
+	client        *elastic.Client
+	bulkProcessor *elastic.BulkProcessor
+	available     bool
+	stopped       bool
+
+	healthcheckCh chan struct{}
+	stopOnce      sync.Once
+}
+
+var _ storage.Storage = (*StorageManager)(nil)
+
+// isAvailable reports whether the last cluster healthcheck succeeded
+func (sm *StorageManager) isAvailable() bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.available
+}
+
+// setAvailable updates the cluster availability flag
+func (sm *StorageManager) setAvailable(available bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.available = available
+}
+
+// getClient returns the current Elasticsearch client, or nil if one hasn't connected yet
+func (sm *StorageManager) getClient() *elastic.Client {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.client
+}
+
+// getBulkProcessor returns the current BulkProcessor, or nil if one hasn't been created yet
+func (sm *StorageManager) getBulkProcessor() *elastic.BulkProcessor {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.bulkProcessor
+}
+
+// connect wires up a successfully dialed client: it builds the BulkProcessor, marks the
+// manager available and starts the background availability checker. If Stop/Close was
+// already called (e.g. a connection attempt that outlived connectTimeout finally succeeds
+// after shutdown), the client/processor are torn down instead of adopted.
+func (sm *StorageManager) connect(client *elastic.Client, bulkConf config.BulkIndexingConfig) error {
+	if sm.isStopped() {
+		client.Stop()
+		return nil
+	}
+
+	bulkProcessor, err := getBulkProcessor(client, bulkConf)
+	if err != nil {
+		return err
+	}
+
+	sm.mu.Lock()
+	if sm.stopped {
+		sm.mu.Unlock()
+		bulkProcessor.Close()
+		client.Stop()
+		return nil
+	}
+	sm.client = client
+	sm.bulkProcessor = bulkProcessor
+	sm.available = true
+	sm.mu.Unlock()
+
+	go sm.watchAvailability()
+
+	return nil
+}
+
+// isStopped reports whether Stop/Close has been called
+func (sm *StorageManager) isStopped() bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.stopped
+}
+
+// watchAvailability periodically pings the cluster and flips the available flag,
+// similar to Gitea's ElasticSearchIndexer availability watcher
+func (sm *StorageManager) watchAvailability() {
+	ticker := time.NewTicker(availabilityCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_, err := sm.getClient().ClusterHealth().Do(context.Background())
+			available := err == nil
+			if available != sm.isAvailable() {
+				log.WithField("available", available).Info("elasticsearch cluster availability changed")
+			}
+			sm.setAvailable(available)
+		case <-sm.healthcheckCh:
+			return
+		}
+	}
+}
+
+// Stop stops the background availability checker. It is safe to call more than once,
+// including concurrently.
+func (sm *StorageManager) Stop() {
+	sm.stopOnce.Do(func() {
+		sm.mu.Lock()
+		sm.stopped = true
+		sm.mu.Unlock()
+		close(sm.healthcheckCh)
+	})
+}
+
+// getHTTPClient builds the *http.Client used to talk to Elasticsearch, wiring up TLS
+// (CA bundle, client certificate, or InsecureSkipVerify) when configured
+func getHTTPClient(conf config.TLSConfig) (*http.Client, error) {
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: conf.InsecureSkipVerify} // nolint:gosec
+
+	if conf.CAFile != "" {
+		caCert, err := ioutil.ReadFile(conf.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read TLS CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("could not parse TLS CA file %s", conf.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if conf.CertFile != "" && conf.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load TLS client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}, nil
+}
+
+// decodeCloudID decodes an Elastic Cloud ID into its Elasticsearch endpoint, following
+// the "deploymentName:base64(esHost$esUUID$kibanaUUID)" format used by Elastic Cloud
+func decodeCloudID(cloudID string) (string, error) {
+
+	parts := strings.SplitN(cloudID, ":", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("malformed cloud id %q", cloudID)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("could not decode cloud id: %w", err)
+	}
+
+	segments := strings.Split(string(decoded), "$")
+	if len(segments) < 2 {
+		return "", fmt.Errorf("malformed cloud id %q", cloudID)
+	}
+
+	host, esUUID := segments[0], segments[1]
+	return fmt.Sprintf("https://%s.%s", esUUID, host), nil
 }
 
 // getESClient create new elasticsearch client
 func getESClient(conf config.ElasticsearchConfig) (*elastic.Client, error) {
 
-	client, err := elastic.NewClient(elastic.SetURL(strings.Join(conf.Endpoints, ",")),
+	endpoints := conf.Endpoints
+	if conf.CloudID != "" {
+		endpoint, err := decodeCloudID(conf.CloudID)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = []string{endpoint}
+	}
+
+	httpClient, err := getHTTPClient(conf.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []elastic.ClientOptionFunc{
+		elastic.SetURL(strings.Join(endpoints, ",")),
 		elastic.SetErrorLog(log.New()),
 		//elastic.SetTraceLog(log.New()), // Uncomment for debugging ElasticSearch Queries
-		elastic.SetBasicAuth(conf.Username, conf.Password),
 		elastic.SetSniff(false),
-		elastic.SetHealthcheck(true))
+		elastic.SetHealthcheck(true),
+		elastic.SetHttpClient(httpClient),
+		elastic.SetGzip(conf.Gzip),
+	}
+
+	if conf.APIKey != "" {
+		options = append(options, elastic.SetHeaders(http.Header{
+			"Authorization": []string{"ApiKey " + conf.APIKey},
+		}))
+	} else {
+		options = append(options, elastic.SetBasicAuth(conf.Username, conf.Password))
+	}
+
+	if conf.HealthcheckInterval > 0 {
+		options = append(options, elastic.SetHealthcheckInterval(conf.HealthcheckInterval))
+	}
+	if conf.SnifferTimeout > 0 {
+		options = append(options, elastic.SetSnifferTimeout(conf.SnifferTimeout))
+	}
+
+	return elastic.NewClient(options...)
+}
+
+// getBulkProcessor creates and starts the BulkProcessor used by Save to batch documents
+func getBulkProcessor(client *elastic.Client, conf config.BulkIndexingConfig) (*elastic.BulkProcessor, error) {
+
+	workers := conf.Workers
+	if workers <= 0 {
+		workers = defaultBulkWorkers
+	}
+	bulkActions := conf.BulkActions
+	if bulkActions <= 0 {
+		bulkActions = defaultBulkActions
+	}
+	bulkSize := conf.BulkSize
+	if bulkSize <= 0 {
+		bulkSize = defaultBulkSize
+	}
+	flushInterval := conf.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultFlushInterval
+	}
+
+	return client.BulkProcessor().
+		Name("finala-bulk-processor").
+		Workers(workers).
+		BulkActions(bulkActions).
+		BulkSize(bulkSize).
+		FlushInterval(flushInterval).
+		Backoff(elastic.NewExponentialBackoff(100*time.Millisecond, 60*time.Second)).
+		After(bulkAfterCommit).
+		Stats(true).
+		Do(context.Background())
+}
 
-	return client, err
+// bulkAfterCommit logs failures reported by the BulkProcessor after each commit
+func bulkAfterCommit(executionID int64, requests []elastic.BulkableRequest, response *elastic.BulkResponse, err error) {
 
+	if err != nil {
+		log.WithError(err).WithField("bulk_execution_id", executionID).Error("bulk commit failed")
+		return
+	}
+
+	if response != nil && response.Errors {
+		for _, failed := range response.Failed() {
+			log.WithFields(log.Fields{
+				"bulk_execution_id": executionID,
+				"index":             failed.Index,
+				"status":            failed.Status,
+			}).Error("failed to index document")
+		}
+	}
 }
 
-// NewStorageManager creates new elasticsearch storage
+// connectTimeout bounds how long NewStorageManager waits for the initial connection
+// before returning a StorageManager that isn't available yet
+const connectTimeout = 60 * time.Second
+
+// NewStorageManager creates new elasticsearch storage. If Elasticsearch isn't reachable
+// within connectTimeout, a StorageManager is still returned, with available false; the
+// connection attempt keeps retrying in the background, cancelable via Stop/Close, and the
+// manager becomes available once it succeeds.
 func NewStorageManager(conf config.ElasticsearchConfig) (*StorageManager, error) {
 
-	var esclient *elastic.Client
+	storageManager := &StorageManager{
+		defaultIndex:  conf.Index,
+		healthcheckCh: make(chan struct{}),
+	}
 
-	c := make(chan int, 1)
+	connected := make(chan *elastic.Client, 1)
 	go func() {
-		var err error
 		for {
-			esclient, err = getESClient(conf)
+			esclient, err := getESClient(conf)
 			if err == nil {
-				break
+				connected <- esclient
+				return
 			}
 			log.WithFields(log.Fields{
 				"endpoint": conf.Endpoints,
 			}).WithError(err).Warn("could not initialize connection to elasticsearch, retrying in 5 seconds")
-			time.Sleep(5 * time.Second)
+
+			select {
+			case <-storageManager.healthcheckCh:
+				return
+			case <-time.After(5 * time.Second):
+			}
 		}
-		c <- 1
 	}()
 
 	select {
-	case <-c:
-	case <-time.After(60 * time.Second):
-		log.Fatal("could not connect elasticsearch, timed out after 1 minute")
-	}
-
-	storageManager := &StorageManager{
-		client:       esclient,
-		defaultIndex: conf.Index,
+	case esclient := <-connected:
+		if err := storageManager.connect(esclient, conf.BulkIndexing); err != nil {
+			return nil, err
+		}
+	case <-time.After(connectTimeout):
+		log.WithFields(log.Fields{
+			"endpoint": conf.Endpoints,
+		}).Warn("could not connect to elasticsearch within the timeout, will keep retrying in the background")
+
+		go func() {
+			select {
+			case esclient := <-connected:
+				if err := storageManager.connect(esclient, conf.BulkIndexing); err != nil {
+					log.WithError(err).Error("could not finish connecting to elasticsearch")
+				}
+			case <-storageManager.healthcheckCh:
+				// Stop/Close was called before a connection was ever established.
+			}
+		}()
 	}
 
 	return storageManager, nil
 }
 
-// Save new documents
+// Save enqueues a document for indexing and returns without waiting for it to be committed.
+// Documents are batched and flushed asynchronously by the underlying BulkProcessor; use
+// Close to drain pending documents during shutdown.
 func (sm *StorageManager) Save(data string) bool {
 
-	_, err := sm.client.Index().
-		Index(sm.defaultIndex).
-		BodyJson(data).
-		Do(context.Background())
-
-	if err != nil {
-		log.WithFields(log.Fields{
-			"index": sm.defaultIndex,
-			"data":  data,
-		}).WithError(err).Error("Fail to save document")
+	if !sm.isAvailable() {
+		log.WithError(storage.ErrStorageUnavailable).Error("could not save document")
 		return false
 	}
 
+	request := elastic.NewBulkIndexRequest().
+		Index(sm.defaultIndex).
+		Doc(json.RawMessage(data))
+
+	sm.getBulkProcessor().Add(request)
+
 	return true
 
 }
 
+// Close flushes any pending documents, stops the BulkProcessor and the availability checker
+func (sm *StorageManager) Close(ctx context.Context) error {
+
+	sm.Stop()
+
+	bulkProcessor := sm.getBulkProcessor()
+	if bulkProcessor == nil {
+		// Never finished connecting, so there's nothing to flush.
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- bulkProcessor.Close()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // getDynamicMatchQuery will iterate through a filters map and create Match Query for each of them
 func (sm *StorageManager) getDynamicMatchQuery(filters map[string]string) []elastic.Query {
 	dynamicMatchQuery := []elastic.Query{}
@@ -127,6 +438,11 @@ func (sm *StorageManager) getDynamicMatchQuery(filters map[string]string) []elas
 // GetSummary returns executions summary
 func (sm *StorageManager) GetSummary(executionID string, filters map[string]string) (map[string]storage.CollectorsSummary, error) {
 	summary := map[string]storage.CollectorsSummary{}
+
+	if !sm.isAvailable() {
+		return summary, storage.ErrStorageUnavailable
+	}
+
 	executionIDQuery := elastic.NewMatchQuery("ExecutionID", executionID)
 	eventTypeQuery := elastic.NewMatchQuery("EventType", "service_status")
 
@@ -135,7 +451,7 @@ func (sm *StorageManager) GetSummary(executionID string, filters map[string]stri
 		"event_type":   eventTypeQuery,
 	}).Debug("Going to get get summary with the following fields")
 
-	searchResult, err := sm.client.Search().
+	searchResult, err := sm.getClient().Search().
 		Query(elastic.NewBoolQuery().Must(eventTypeQuery, executionIDQuery)).
 		Pretty(true).
 		Size(100).
@@ -201,11 +517,15 @@ func (sm *StorageManager) getResourceSummaryDetails(executionID string, filters
 	var totalSpent float64
 	var resourceCount int64
 
+	if !sm.isAvailable() {
+		return totalSpent, resourceCount, storage.ErrStorageUnavailable
+	}
+
 	dynamicMatchQuery := sm.getDynamicMatchQuery(filters)
 	dynamicMatchQuery = append(dynamicMatchQuery, elastic.NewMatchQuery("ExecutionID", executionID))
 	dynamicMatchQuery = append(dynamicMatchQuery, elastic.NewMatchQuery("EventType", "resource_detected"))
 
-	searchResult, err := sm.client.Search().
+	searchResult, err := sm.getClient().Search().
 		Query(elastic.NewBoolQuery().Must(dynamicMatchQuery...)).
 		Aggregation("sum", elastic.NewSumAggregation().Field("Data.PricePerMonth")).
 		Size(0).Do(context.Background())
@@ -236,85 +556,131 @@ func (sm *StorageManager) getResourceSummaryDetails(executionID string, filters
 	return totalSpent, resourceCount, nil
 }
 
-// GetExecutions returns collector executions
-func (sm *StorageManager) GetExecutions(queryLimit int) ([]storage.Executions, error) {
-	executions := []storage.Executions{}
-
-	// First search for all message with eventType: service_status
-	// Second look for message which have the field ExecutionID
-	// Third Order the ExecutionID by EventTime Desc
-	searchResult, err := sm.client.Search().Aggregation("orderedExecutionID", elastic.NewFiltersAggregation().
-		Filters(elastic.NewBoolQuery().Filter(elastic.NewBoolQuery().Should(elastic.NewMatchQuery("EventType", "service_status")))).
-		SubAggregation("ExecutionIDDesc", elastic.NewTermsAggregation().Field("ExecutionID.keyword").Size(queryLimit).Order("MaxEventTime", false).
-			SubAggregation("MaxEventTime", elastic.NewMaxAggregation().Field("EventTime")))).
+// GetExecutions returns collector executions using a composite aggregation on ExecutionID
+// so callers can page through long execution histories via page.After instead of losing
+// results past a fixed size cap. Composite aggregations can only be ordered by their
+// source key values, not by a sub-aggregation metric, so pages themselves are fetched in
+// ascending ExecutionID order, not chronological order; only the executions within a single
+// page are re-sorted by execution time (most recent first) before being returned. Unlike
+// the Postgres backend's ORDER BY last_event_time DESC keyset pagination, callers paging
+// across multiple pages on this backend will see batches that are not globally time-ordered.
+func (sm *StorageManager) GetExecutions(page storage.PageRequest) (storage.ExecutionsPage, error) {
+	result := storage.ExecutionsPage{Executions: []storage.Executions{}}
+
+	if !sm.isAvailable() {
+		return result, storage.ErrStorageUnavailable
+	}
+
+	limit := page.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+
+	composite := elastic.NewCompositeAggregation().
+		Size(limit).
+		Sources(elastic.NewCompositeAggregationTermsValuesSource("ExecutionID").Field("ExecutionID.keyword"))
+
+	if page.After != "" {
+		composite = composite.AggregateAfter(map[string]interface{}{"ExecutionID": page.After})
+	}
+
+	searchResult, err := sm.getClient().Search().
+		Query(elastic.NewMatchQuery("EventType", "service_status")).
+		Size(0).
+		Aggregation("executions", composite).
 		Do(context.Background())
 
-	if nil != err {
-		log.WithError(err).WithFields(log.Fields{
-			"milliseconds": searchResult.TookInMillis,
-		}).Error("error when trying to get executions collectors")
-		return executions, nil
+	if err != nil {
+		log.WithError(err).Error("error when trying to get executions collectors")
+		return result, err
 	}
 
-	resp, ok := searchResult.Aggregations.Terms("orderedExecutionID")
+	resp, ok := searchResult.Aggregations.Composite("executions")
 	if !ok {
-		log.Error("orderedExecutionID field term does not exist")
-		return executions, nil
+		log.Error("executions composite aggregation does not exist")
+		return result, nil
 	}
 
-	for _, ExecutionIDBuckets := range resp.Buckets {
-		descOrderedExecutionIDs := ExecutionIDBuckets.Aggregations["ExecutionIDDesc"]
+	for _, bucket := range resp.Buckets {
+		executionID, ok := bucket.Key["ExecutionID"].(string)
+		if !ok {
+			continue
+		}
+
+		data := strings.Split(executionID, "_")
+
+		// Remove the last element of Data which is the timestamp and leave all the others elements
+		// Which construct the executionName
+		executionName := strings.Join(data[:len(data)-1], "_")
 
-		var executionsIDs orderedExecutionIDs
-		err := json.Unmarshal([]byte(string(descOrderedExecutionIDs)), &executionsIDs)
+		// Always take the last element which is the timestamp of the collector's run
+		collectorExecutionTime, err := strconv.ParseInt(data[len(data)-1], 10, 64)
 		if err != nil {
-			log.WithError(err).Error("error when trying to parse bucket aggregations execution ids")
+			log.WithError(err).WithField("collector_execution_time", collectorExecutionTime).Error("could not parse to int64")
+			continue
 		}
 
-		for _, executionIDValue := range executionsIDs.Buckets {
-			executionID := string(executionIDValue.Key)
-			data := strings.Split(executionID, "_")
+		result.Executions = append(result.Executions, storage.Executions{
+			ID:   executionID,
+			Name: executionName,
+			Time: time.Unix(collectorExecutionTime, 0),
+		})
+	}
 
-			// Remove the last element of Data which is the timestamp and leave all the others elements
-			// Which construct the executionName
-			executionName := strings.Join(data[:len(data)-1], "_")
+	sort.Slice(result.Executions, func(i, j int) bool {
+		return result.Executions[i].Time.After(result.Executions[j].Time)
+	})
 
-			// Always take the last element which is the timestamp of the collector's run
-			collectorExecutionTime, err := strconv.ParseInt(data[len(data)-1], 10, 64)
-			if err != nil {
-				log.WithError(err).WithField("collector_execution_time", collectorExecutionTime).Error("could not parse to int64")
-				continue
-			}
-
-			executions = append(executions, storage.Executions{
-				ID:   executionID,
-				Name: executionName,
-				Time: time.Unix(collectorExecutionTime, 0),
-			})
+	if len(resp.Buckets) == limit {
+		if executionID, ok := resp.AfterKey["ExecutionID"].(string); ok {
+			result.After = executionID
 		}
 	}
-	return executions, nil
+
+	return result, nil
 }
 
-// GetResources return resource data
-func (sm *StorageManager) GetResources(resourceType string, executionID string) ([]map[string]interface{}, error) {
+// GetResources return resource data, paging through results via the search_after
+// pattern instead of the previous hardcoded Size(100) cap.
+func (sm *StorageManager) GetResources(resourceType string, executionID string, page storage.PageRequest) (storage.ResourcesPage, error) {
+
+	result := storage.ResourcesPage{}
+
+	if !sm.isAvailable() {
+		return result, storage.ErrStorageUnavailable
+	}
+
+	limit := page.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
 
-	var resources []map[string]interface{}
 	componentQ := elastic.NewMatchQuery("EventType", "resource_detected")
 	deploymentQ := elastic.NewMatchQuery("ExecutionID", executionID)
 	ResourceNameQ := elastic.NewMatchQuery("ResourceName", resourceType)
 	generalQ := elastic.NewBoolQuery()
 	generalQ = generalQ.Must(componentQ).Must(deploymentQ).Must(ResourceNameQ)
 
-	searchResult, err := sm.client.Search().
+	search := sm.getClient().Search().
 		Query(generalQ).
-		Pretty(true).
-		Size(100).
-		Do(context.Background())
+		Sort("EventTime", true).
+		Sort("_id", true).
+		Size(limit)
+
+	if page.After != "" {
+		eventTime, id, err := decodeResourcesAfter(page.After)
+		if err != nil {
+			log.WithError(err).WithField("after", page.After).Error("could not decode resources page cursor")
+		} else {
+			search = search.SearchAfter(eventTime, id)
+		}
+	}
+
+	searchResult, err := search.Do(context.Background())
 
 	if err != nil {
 		log.WithError(err).Error("elasticsearch query error")
-		return resources, err
+		return result, err
 	}
 
 	for _, hit := range searchResult.Hits.Hits {
@@ -323,18 +689,69 @@ func (sm *StorageManager) GetResources(resourceType string, executionID string)
 		err := json.Unmarshal([]byte(string(hit.Source)), &rowData)
 		if err != nil {
 			log.WithError(err).Error("error when trying to parse search result hits data")
+			continue
 		}
 
-		resources = append(resources, rowData)
+		result.Resources = append(result.Resources, rowData)
+	}
+
+	if len(searchResult.Hits.Hits) == limit {
+		lastHit := searchResult.Hits.Hits[len(searchResult.Hits.Hits)-1]
+		if len(lastHit.Sort) == 2 {
+			result.After = encodeResourcesAfter(lastHit.Sort[0], lastHit.Sort[1])
+		}
+	}
+
+	return result, nil
+}
+
+// encodeResourcesAfter packs a GetResources search_after sort tuple into an opaque cursor.
+// olivere/elastic unmarshals numeric sort values as float64, so eventTime must be formatted
+// without the exponent notation fmt's default %v verb would use for large values.
+func encodeResourcesAfter(eventTime, id interface{}) string {
+	return fmt.Sprintf("%s|%v", formatEventTimeSort(eventTime), id)
+}
+
+// formatEventTimeSort renders an ES sort value for eventTime as a plain base-10 integer,
+// regardless of whether it arrived as float64, json.Number, int64 or something else.
+func formatEventTimeSort(eventTime interface{}) string {
+	switch v := eventTime.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int:
+		return strconv.Itoa(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// decodeResourcesAfter unpacks a cursor produced by encodeResourcesAfter
+func decodeResourcesAfter(after string) (int64, string, error) {
+	parts := strings.SplitN(after, "|", 2)
+	if len(parts) != 2 {
+		return 0, "", fmt.Errorf("malformed page cursor %q", after)
+	}
+
+	eventTime, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		eventTimeFloat, floatErr := strconv.ParseFloat(parts[0], 64)
+		if floatErr != nil {
+			return 0, "", fmt.Errorf("malformed page cursor %q: %w", after, err)
+		}
+		eventTime = int64(eventTimeFloat)
 	}
 
-	return resources, nil
+	return eventTime, parts[1], nil
 }
 
 // createIndex creating create elasticsearch index if not exists
 func (sm *StorageManager) createIndex(index string) {
 
-	exists, err := sm.client.IndexExists(index).Do(context.Background())
+	exists, err := sm.getClient().IndexExists(index).Do(context.Background())
 	if err != nil {
 		log.WithFields(log.Fields{
 			"index": index,
@@ -347,7 +764,7 @@ func (sm *StorageManager) createIndex(index string) {
 	}
 
 	ctx := context.Background()
-	_, err = sm.client.CreateIndex(index).BodyString(indexMapping).Do(ctx)
+	_, err = sm.getClient().CreateIndex(index).BodyString(indexMapping).Do(ctx)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"index": index,
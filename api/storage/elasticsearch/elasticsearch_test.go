@@ -0,0 +1,45 @@
+package elasticsearch
+
+import "testing"
+
+func TestEncodeDecodeResourcesAfter(t *testing.T) {
+	tests := []struct {
+		name      string
+		eventTime interface{}
+		id        interface{}
+		wantTime  int64
+		wantID    string
+	}{
+		{name: "int eventTime", eventTime: 1700000000, id: "doc-1", wantTime: 1700000000, wantID: "doc-1"},
+		{name: "float64 eventTime", eventTime: float64(1700000001), id: "doc-2", wantTime: 1700000001, wantID: "doc-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := encodeResourcesAfter(tt.eventTime, tt.id)
+
+			gotTime, gotID, err := decodeResourcesAfter(cursor)
+			if err != nil {
+				t.Fatalf("decodeResourcesAfter(%q) returned an error: %v", cursor, err)
+			}
+			if gotTime != tt.wantTime || gotID != tt.wantID {
+				t.Errorf("decodeResourcesAfter(%q) = (%d, %q), want (%d, %q)",
+					cursor, gotTime, gotID, tt.wantTime, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestDecodeResourcesAfterMalformed(t *testing.T) {
+	malformed := []string{
+		"",
+		"no-separator",
+		"not-a-number|doc-1",
+	}
+
+	for _, cursor := range malformed {
+		if _, _, err := decodeResourcesAfter(cursor); err == nil {
+			t.Errorf("decodeResourcesAfter(%q) should have returned an error", cursor)
+		}
+	}
+}
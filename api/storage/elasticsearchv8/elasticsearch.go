@@ -0,0 +1,646 @@
+// Package elasticsearchv8 implements storage.Storage on top of the official
+// elastic/go-elasticsearch v8 client. olivere/elastic (used by the sibling elasticsearch
+// package) is unmaintained past Elasticsearch 7 and this client is required for clusters
+// running Elasticsearch 8 or OpenSearch 2.
+package elasticsearchv8
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"finala/api/config"
+	"finala/api/storage"
+
+	es "github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultBulkWorkers is used when config.BulkIndexing.Workers is not set
+	defaultBulkWorkers = 1
+	// defaultBulkSize is used when config.BulkIndexing.BulkSize is not set
+	defaultBulkSize = 2 << 20 // 2MB
+	// defaultFlushInterval is used when config.BulkIndexing.FlushInterval is not set
+	defaultFlushInterval = 10 * time.Second
+)
+
+// availabilityCheckInterval is how often the cluster healthcheck goroutine pings Elasticsearch
+const availabilityCheckInterval = 10 * time.Second
+
+// indexMapping defines the default index mapping, kept in sync with the olivere/elastic
+// based implementation
+const indexMapping = `{
+	"mappings":{
+		"properties":{
+			"ResourceName":{
+				"type":"keyword"
+			},
+			"ExecutionID":{
+				"type":"keyword"
+			},
+			"EventType":{
+				"type":"keyword"
+			}
+		}
+	}
+}`
+
+// StorageManager implements storage.Storage using the go-elasticsearch v8 client
+type StorageManager struct {
+	client       *es.Client
+	bulkIndexer  esutil.BulkIndexer
+	defaultIndex string
+
+	mu            sync.RWMutex
+	available     bool
+	healthcheckCh chan struct{}
+	stopOnce      sync.Once
+}
+
+var _ storage.Storage = (*StorageManager)(nil)
+
+// isAvailable reports whether the last cluster healthcheck succeeded
+func (sm *StorageManager) isAvailable() bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.available
+}
+
+// setAvailable updates the cluster availability flag
+func (sm *StorageManager) setAvailable(available bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.available = available
+}
+
+// checkAvailability pings the cluster once and reports whether it responded successfully
+func (sm *StorageManager) checkAvailability() bool {
+	resp, err := sm.client.Cluster.Health(sm.client.Cluster.Health.WithContext(context.Background()))
+	available := err == nil && !resp.IsError()
+	if resp != nil {
+		resp.Body.Close()
+	}
+	return available
+}
+
+// watchAvailability periodically pings the cluster and flips the available flag, mirroring
+// the olivere/elastic based backend's availability watcher
+func (sm *StorageManager) watchAvailability() {
+	ticker := time.NewTicker(availabilityCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			available := sm.checkAvailability()
+			if available != sm.isAvailable() {
+				log.WithField("available", available).Info("elasticsearch cluster availability changed")
+			}
+			sm.setAvailable(available)
+		case <-sm.healthcheckCh:
+			return
+		}
+	}
+}
+
+// Stop stops the background availability checker. It is safe to call more than once,
+// including concurrently.
+func (sm *StorageManager) Stop() {
+	sm.stopOnce.Do(func() {
+		close(sm.healthcheckCh)
+	})
+}
+
+// getTransport builds the http.RoundTripper used to talk to Elasticsearch, wiring up TLS
+// (CA bundle, client certificate, or InsecureSkipVerify) when configured. Kept in sync with
+// the olivere/elastic based implementation's getHTTPClient.
+func getTransport(conf config.TLSConfig) (http.RoundTripper, error) {
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: conf.InsecureSkipVerify} // nolint:gosec
+
+	if conf.CAFile != "" {
+		caCert, err := ioutil.ReadFile(conf.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read TLS CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("could not parse TLS CA file %s", conf.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if conf.CertFile != "" && conf.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load TLS client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return &http.Transport{TLSClientConfig: tlsConfig}, nil
+}
+
+// NewStorageManager creates a new v8-client backed Elasticsearch storage.
+//
+// HealthcheckInterval is wired through as DiscoverNodesInterval, the closest v8 client
+// equivalent of the v7 client's periodic sniffing. SnifferTimeout has no equivalent in the
+// v8 client's connection pool and only takes effect for api_version: 7.
+func NewStorageManager(conf config.ElasticsearchConfig) (*StorageManager, error) {
+
+	transport, err := getTransport(conf.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	// The client hard-errors if both Addresses and CloudID are set, so CloudID takes
+	// precedence, matching the v7 backend's getESClient behavior.
+	endpoints := conf.Endpoints
+	if conf.CloudID != "" {
+		endpoints = nil
+	}
+
+	esConfig := es.Config{
+		Addresses:             endpoints,
+		Username:              conf.Username,
+		Password:              conf.Password,
+		CloudID:               conf.CloudID,
+		APIKey:                conf.APIKey,
+		CompressRequestBody:   conf.Gzip,
+		Transport:             transport,
+		DiscoverNodesOnStart:  conf.HealthcheckInterval > 0,
+		DiscoverNodesInterval: conf.HealthcheckInterval,
+	}
+
+	client, err := es.NewClient(esConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not create elasticsearch v8 client: %w", err)
+	}
+
+	bulkWorkers := conf.BulkIndexing.Workers
+	if bulkWorkers <= 0 {
+		bulkWorkers = defaultBulkWorkers
+	}
+	bulkSize := conf.BulkIndexing.BulkSize
+	if bulkSize <= 0 {
+		bulkSize = defaultBulkSize
+	}
+	flushInterval := conf.BulkIndexing.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultFlushInterval
+	}
+
+	bulkIndexer, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
+		Client:        client,
+		Index:         conf.Index,
+		NumWorkers:    bulkWorkers,
+		FlushBytes:    bulkSize,
+		FlushInterval: flushInterval,
+		OnError: func(ctx context.Context, err error) {
+			log.WithError(err).Error("bulk indexer error")
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create bulk indexer: %w", err)
+	}
+
+	storageManager := &StorageManager{
+		client:        client,
+		bulkIndexer:   bulkIndexer,
+		defaultIndex:  conf.Index,
+		healthcheckCh: make(chan struct{}),
+	}
+	storageManager.setAvailable(storageManager.checkAvailability())
+
+	storageManager.createIndex(conf.Index)
+
+	go storageManager.watchAvailability()
+
+	return storageManager, nil
+}
+
+// createIndex creates the default index if it does not already exist
+func (sm *StorageManager) createIndex(index string) {
+
+	existsResp, err := sm.client.Indices.Exists([]string{index})
+	if err != nil {
+		log.WithField("index", index).WithError(err).Error("Error when trying to check if elasticsearch index exists")
+		return
+	}
+	defer existsResp.Body.Close()
+
+	if existsResp.StatusCode == 200 {
+		log.WithField("index", index).Info("index already exists")
+		return
+	}
+
+	createResp, err := sm.client.Indices.Create(index, sm.client.Indices.Create.WithBody(strings.NewReader(indexMapping)))
+	if err != nil {
+		log.WithField("index", index).WithError(err).Error("Error when trying to create elasticsearch index")
+		return
+	}
+	defer createResp.Body.Close()
+
+	if createResp.IsError() {
+		log.WithField("index", index).WithField("response", createResp.String()).Error("Error when trying to create elasticsearch index")
+	}
+}
+
+// Save enqueues a document for indexing via the BulkIndexer
+func (sm *StorageManager) Save(data string) bool {
+
+	if !sm.isAvailable() {
+		log.WithError(storage.ErrStorageUnavailable).Error("could not save document")
+		return false
+	}
+
+	err := sm.bulkIndexer.Add(context.Background(), esutil.BulkIndexerItem{
+		Action: "index",
+		Body:   strings.NewReader(data),
+		OnFailure: func(ctx context.Context, item esutil.BulkIndexerItem, resp esutil.BulkIndexerResponseItem, err error) {
+			log.WithFields(log.Fields{
+				"index": sm.defaultIndex,
+				"data":  data,
+			}).WithError(err).Error("Fail to save document")
+		},
+	})
+
+	return err == nil
+}
+
+// Close flushes and stops the BulkIndexer and the availability checker
+func (sm *StorageManager) Close(ctx context.Context) error {
+	sm.Stop()
+	return sm.bulkIndexer.Close(ctx)
+}
+
+// defaultPageLimit is used for GetExecutions/GetResources when page.Limit is not set
+const defaultPageLimit = 100
+
+// searchHits is the subset of the ES search response shape we need to decode
+type searchHits struct {
+	Hits struct {
+		Total struct {
+			Value int64 `json:"value"`
+		} `json:"total"`
+		Hits []struct {
+			Source json.RawMessage   `json:"_source"`
+			Sort   []json.RawMessage `json:"sort"`
+		} `json:"hits"`
+	} `json:"hits"`
+	Aggregations json.RawMessage `json:"aggregations"`
+}
+
+// search runs a raw query DSL body against the default index and decodes the hits
+func (sm *StorageManager) search(body map[string]interface{}) (*searchHits, error) {
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := sm.client.Search(
+		sm.client.Search.WithContext(context.Background()),
+		sm.client.Search.WithIndex(sm.defaultIndex),
+		sm.client.Search.WithBody(bytes.NewReader(encoded)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.IsError() {
+		return nil, fmt.Errorf("elasticsearch query error: %s", resp.String())
+	}
+
+	var result searchHits
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetSummary returns executions summary
+func (sm *StorageManager) GetSummary(executionID string, filters map[string]string) (map[string]storage.CollectorsSummary, error) {
+	summary := map[string]storage.CollectorsSummary{}
+
+	if !sm.isAvailable() {
+		return summary, storage.ErrStorageUnavailable
+	}
+
+	result, err := sm.search(map[string]interface{}{
+		"size": 100,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": []map[string]interface{}{
+					{"match": map[string]interface{}{"EventType": "service_status"}},
+					{"match": map[string]interface{}{"ExecutionID": executionID}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.WithError(err).Error("error when trying to get summary data")
+		return summary, err
+	}
+
+	var summaryData storage.Summary
+	for _, hit := range result.Hits.Hits {
+		if err := json.Unmarshal(hit.Source, &summaryData); err != nil {
+			log.WithError(err).Error("could not parse summary row")
+			continue
+		}
+
+		val, found := summary[summaryData.ResourceName]
+		if found {
+			if summaryData.EventTime < val.EventTime {
+				continue
+			}
+			delete(summary, summaryData.ResourceName)
+		}
+
+		summary[summaryData.ResourceName] = storage.CollectorsSummary{
+			EventTime:    summaryData.EventTime,
+			Status:       summaryData.Data.Status,
+			ResourceName: summaryData.ResourceName,
+			ErrorMessage: summaryData.Data.ErrorMessage,
+		}
+	}
+
+	for resourceName, resourceData := range summary {
+		filters["ResourceName"] = resourceName
+		totalSpent, resourceCount, err := sm.getResourceSummaryDetails(executionID, filters)
+		if err != nil {
+			continue
+		}
+
+		newResourceData := resourceData
+		newResourceData.TotalSpent = totalSpent
+		newResourceData.ResourceCount = resourceCount
+		summary[resourceName] = newResourceData
+	}
+
+	return summary, nil
+}
+
+// getResourceSummaryDetails returns total resource spent and total resources detected
+func (sm *StorageManager) getResourceSummaryDetails(executionID string, filters map[string]string) (float64, int64, error) {
+
+	var totalSpent float64
+	var resourceCount int64
+
+	if !sm.isAvailable() {
+		return totalSpent, resourceCount, storage.ErrStorageUnavailable
+	}
+
+	must := []map[string]interface{}{
+		{"match": map[string]interface{}{"ExecutionID": executionID}},
+		{"match": map[string]interface{}{"EventType": "resource_detected"}},
+	}
+	for name, value := range filters {
+		must = append(must, map[string]interface{}{"match": map[string]interface{}{name: value}})
+	}
+
+	encoded, err := json.Marshal(map[string]interface{}{
+		"size":  0,
+		"query": map[string]interface{}{"bool": map[string]interface{}{"must": must}},
+		"aggs": map[string]interface{}{
+			"sum": map[string]interface{}{
+				"sum": map[string]interface{}{"field": "Data.PricePerMonth"},
+			},
+		},
+	})
+	if err != nil {
+		return totalSpent, resourceCount, err
+	}
+
+	resp, err := sm.client.Search(
+		sm.client.Search.WithContext(context.Background()),
+		sm.client.Search.WithIndex(sm.defaultIndex),
+		sm.client.Search.WithBody(bytes.NewReader(encoded)),
+	)
+	if err != nil {
+		log.WithError(err).WithField("filters", filters).Error("error when trying to get summary details")
+		return totalSpent, resourceCount, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+		} `json:"hits"`
+		Aggregations struct {
+			Sum struct {
+				Value float64 `json:"value"`
+			} `json:"sum"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return totalSpent, resourceCount, err
+	}
+
+	return result.Aggregations.Sum.Value, result.Hits.Total.Value, nil
+}
+
+// GetExecutions returns collector executions using a composite aggregation on ExecutionID,
+// paged via page.After instead of a fixed size cap. Composite aggregations can only be
+// ordered by their source key values, not by a sub-aggregation metric, so pages themselves
+// are fetched in ascending ExecutionID order, not chronological order; only the executions
+// within a single page are re-sorted by execution time (most recent first) before being
+// returned. Unlike the Postgres backend's ORDER BY last_event_time DESC keyset pagination,
+// callers paging across multiple pages on this backend will see batches that are not
+// globally time-ordered.
+func (sm *StorageManager) GetExecutions(page storage.PageRequest) (storage.ExecutionsPage, error) {
+	result := storage.ExecutionsPage{Executions: []storage.Executions{}}
+
+	if !sm.isAvailable() {
+		return result, storage.ErrStorageUnavailable
+	}
+
+	limit := page.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+
+	composite := map[string]interface{}{
+		"size": limit,
+		"sources": []map[string]interface{}{
+			{"ExecutionID": map[string]interface{}{"terms": map[string]interface{}{"field": "ExecutionID.keyword"}}},
+		},
+	}
+	if page.After != "" {
+		composite["after"] = map[string]interface{}{"ExecutionID": page.After}
+	}
+
+	encoded, err := json.Marshal(map[string]interface{}{
+		"size": 0,
+		"query": map[string]interface{}{
+			"match": map[string]interface{}{"EventType": "service_status"},
+		},
+		"aggs": map[string]interface{}{
+			"executions": map[string]interface{}{
+				"composite": composite,
+			},
+		},
+	})
+	if err != nil {
+		return result, err
+	}
+
+	resp, err := sm.client.Search(
+		sm.client.Search.WithContext(context.Background()),
+		sm.client.Search.WithIndex(sm.defaultIndex),
+		sm.client.Search.WithBody(bytes.NewReader(encoded)),
+	)
+	if err != nil {
+		log.WithError(err).Error("error when trying to get executions collectors")
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	var decoded struct {
+		Aggregations struct {
+			Executions struct {
+				AfterKey struct {
+					ExecutionID string `json:"ExecutionID"`
+				} `json:"after_key"`
+				Buckets []struct {
+					Key struct {
+						ExecutionID string `json:"ExecutionID"`
+					} `json:"key"`
+				} `json:"buckets"`
+			} `json:"executions"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		log.WithError(err).Error("error when trying to parse bucket aggregations execution ids")
+		return result, err
+	}
+
+	for _, bucket := range decoded.Aggregations.Executions.Buckets {
+		executionID := bucket.Key.ExecutionID
+		data := strings.Split(executionID, "_")
+
+		// Remove the last element of Data which is the timestamp and leave all the others elements
+		// Which construct the executionName
+		executionName := strings.Join(data[:len(data)-1], "_")
+
+		collectorExecutionTime, err := strconv.ParseInt(data[len(data)-1], 10, 64)
+		if err != nil {
+			log.WithError(err).WithField("collector_execution_time", collectorExecutionTime).Error("could not parse to int64")
+			continue
+		}
+
+		result.Executions = append(result.Executions, storage.Executions{
+			ID:   executionID,
+			Name: executionName,
+			Time: time.Unix(collectorExecutionTime, 0),
+		})
+	}
+
+	sort.Slice(result.Executions, func(i, j int) bool {
+		return result.Executions[i].Time.After(result.Executions[j].Time)
+	})
+
+	if len(decoded.Aggregations.Executions.Buckets) == limit {
+		result.After = decoded.Aggregations.Executions.AfterKey.ExecutionID
+	}
+
+	return result, nil
+}
+
+// GetResources return resource data, paging through results via the search_after pattern
+func (sm *StorageManager) GetResources(resourceType string, executionID string, page storage.PageRequest) (storage.ResourcesPage, error) {
+
+	result := storage.ResourcesPage{}
+
+	if !sm.isAvailable() {
+		return result, storage.ErrStorageUnavailable
+	}
+
+	limit := page.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+
+	body := map[string]interface{}{
+		"size": limit,
+		"sort": []map[string]interface{}{
+			{"EventTime": "asc"},
+			{"_id": "asc"},
+		},
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": []map[string]interface{}{
+					{"match": map[string]interface{}{"EventType": "resource_detected"}},
+					{"match": map[string]interface{}{"ExecutionID": executionID}},
+					{"match": map[string]interface{}{"ResourceName": resourceType}},
+				},
+			},
+		},
+	}
+
+	if page.After != "" {
+		eventTime, id, err := decodeResourcesAfter(page.After)
+		if err != nil {
+			log.WithError(err).WithField("after", page.After).Error("could not decode resources page cursor")
+		} else {
+			body["search_after"] = []interface{}{eventTime, id}
+		}
+	}
+
+	searched, err := sm.search(body)
+	if err != nil {
+		log.WithError(err).Error("elasticsearch query error")
+		return result, err
+	}
+
+	for _, hit := range searched.Hits.Hits {
+		rowData := make(map[string]interface{})
+		if err := json.Unmarshal(hit.Source, &rowData); err != nil {
+			log.WithError(err).Error("error when trying to parse search result hits data")
+			continue
+		}
+		result.Resources = append(result.Resources, rowData)
+	}
+
+	if len(searched.Hits.Hits) == limit {
+		lastHit := searched.Hits.Hits[len(searched.Hits.Hits)-1]
+		if len(lastHit.Sort) == 2 {
+			result.After = fmt.Sprintf("%s|%s", bytes.Trim(lastHit.Sort[0], `"`), bytes.Trim(lastHit.Sort[1], `"`))
+		}
+	}
+
+	return result, nil
+}
+
+// decodeResourcesAfter unpacks a cursor produced by GetResources into its search_after values
+func decodeResourcesAfter(after string) (int64, string, error) {
+	parts := strings.SplitN(after, "|", 2)
+	if len(parts) != 2 {
+		return 0, "", fmt.Errorf("malformed page cursor %q", after)
+	}
+
+	eventTime, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("malformed page cursor %q: %w", after, err)
+	}
+
+	return eventTime, parts[1], nil
+}
@@ -0,0 +1,45 @@
+// Package manager selects and constructs the configured storage.Storage implementation.
+// It lives outside the storage package itself so it can import every backend without
+// creating an import cycle.
+package manager
+
+import (
+	"fmt"
+
+	"finala/api/config"
+	"finala/api/storage"
+	"finala/api/storage/elasticsearch"
+	"finala/api/storage/elasticsearchv8"
+	"finala/api/storage/postgres"
+)
+
+// New builds the Storage implementation selected by conf.Driver
+func New(conf config.StorageConfig) (storage.Storage, error) {
+
+	switch conf.Driver {
+	case "", "elasticsearch":
+		return newElasticsearch(conf.Elasticsearch)
+	case "postgres":
+		return postgres.NewStorageManager(conf.Postgres)
+	case "sqlite":
+		// Reserved for a future backend: the Postgres migrator/queries use $N placeholders
+		// that don't work against SQLite's driver, so this can't just reuse postgres.StorageManager.
+		return nil, fmt.Errorf("storage driver %q is not yet implemented", conf.Driver)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", conf.Driver)
+	}
+}
+
+// newElasticsearch picks the olivere/elastic (v7) or go-elasticsearch (v8) client
+// implementation based on conf.APIVersion
+func newElasticsearch(conf config.ElasticsearchConfig) (storage.Storage, error) {
+
+	switch conf.APIVersion {
+	case 0, 7:
+		return elasticsearch.NewStorageManager(conf)
+	case 8:
+		return elasticsearchv8.NewStorageManager(conf)
+	default:
+		return nil, fmt.Errorf("unsupported elasticsearch api_version %d, expected 7 or 8", conf.APIVersion)
+	}
+}
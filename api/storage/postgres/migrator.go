@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migration is a single versioned schema file, e.g. migrations/V3.sql
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// loadMigrations reads and sorts the embedded V{n}.sql files by their version number
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]migration, 0, len(entries))
+	for _, entry := range entries {
+		version, err := parseMigrationVersion(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		contents, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, migration{
+			version: version,
+			name:    entry.Name(),
+			sql:     string(contents),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+// parseMigrationVersion extracts n from a "V{n}.sql" file name
+func parseMigrationVersion(name string) (int, error) {
+	if !strings.HasPrefix(name, "V") {
+		return 0, fmt.Errorf("migration file %q does not match the V{n}.sql naming convention", name)
+	}
+
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(name, "V"), ".sql")
+	version, err := strconv.Atoi(trimmed)
+	if err != nil {
+		return 0, fmt.Errorf("migration file %q does not match the V{n}.sql naming convention: %w", name, err)
+	}
+	return version, nil
+}
+
+// migrate applies every migration whose version is greater than the version currently
+// recorded in the "schema" table, each inside its own transaction, as in the evebox
+// migrator pattern
+func migrate(db *sql.DB) error {
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema (version INTEGER NOT NULL)`); err != nil {
+		return fmt.Errorf("could not create schema table: %w", err)
+	}
+
+	currentVersion, err := currentSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return fmt.Errorf("could not load migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= currentVersion {
+			continue
+		}
+
+		if err := applyMigration(db, m); err != nil {
+			return fmt.Errorf("could not apply migration %s: %w", m.name, err)
+		}
+
+		log.WithFields(log.Fields{
+			"migration": m.name,
+			"version":   m.version,
+		}).Info("applied schema migration")
+	}
+
+	return nil
+}
+
+// currentSchemaVersion returns the highest version recorded in the schema table, or 0
+func currentSchemaVersion(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("could not read current schema version: %w", err)
+	}
+	return version, nil
+}
+
+// applyMigration runs a single migration's SQL and records its version, all in one transaction
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.Exec(m.sql); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO schema (version) VALUES ($1)`, m.version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
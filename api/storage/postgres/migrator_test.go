@@ -0,0 +1,36 @@
+package postgres
+
+import "testing"
+
+func TestParseMigrationVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    int
+		wantErr bool
+	}{
+		{name: "single digit", file: "V1.sql", want: 1},
+		{name: "multiple digits", file: "V23.sql", want: 23},
+		{name: "missing V prefix", file: "1.sql", wantErr: true},
+		{name: "missing sql suffix", file: "V1.txt", wantErr: true},
+		{name: "non-numeric version", file: "Vx.sql", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMigrationVersion(tt.file)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseMigrationVersion(%q) should have returned an error", tt.file)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseMigrationVersion(%q) returned an error: %v", tt.file, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseMigrationVersion(%q) = %d, want %d", tt.file, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"finala/api/config"
+	"finala/api/storage"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// the postgres driver registers itself with database/sql
+	_ "github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+)
+
+// document is the shape of the JSON payload collectors send to Save, flattened into the
+// resource_events columns
+type document struct {
+	ExecutionID  string `json:"ExecutionID"`
+	EventType    string `json:"EventType"`
+	ResourceName string `json:"ResourceName"`
+	EventTime    int64  `json:"EventTime"`
+	Data         struct {
+		Status        string  `json:"Status"`
+		ErrorMessage  string  `json:"ErrorMessage"`
+		PricePerMonth float64 `json:"PricePerMonth"`
+	} `json:"Data"`
+}
+
+// StorageManager describes a Postgres backed storage implementation
+type StorageManager struct {
+	db *sql.DB
+}
+
+var _ storage.Storage = (*StorageManager)(nil)
+
+// NewStorageManager opens a connection pool to the database, runs any pending schema
+// migrations and returns a ready to use StorageManager
+func NewStorageManager(conf config.SQLStorageConfig) (*StorageManager, error) {
+
+	db, err := sql.Open("postgres", conf.DataSourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	if err := migrate(db); err != nil {
+		return nil, err
+	}
+
+	return &StorageManager{db: db}, nil
+}
+
+// Close closes the underlying connection pool
+func (sm *StorageManager) Close(ctx context.Context) error {
+	return sm.db.Close()
+}
+
+// Save stores a single collector event document
+func (sm *StorageManager) Save(data string) bool {
+
+	var doc document
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		log.WithError(err).WithField("data", data).Error("could not parse document")
+		return false
+	}
+
+	_, err := sm.db.Exec(`
+		INSERT INTO resource_events
+			(execution_id, event_type, resource_name, event_time, price_per_month, status, error_message, data)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		doc.ExecutionID, doc.EventType, doc.ResourceName, doc.EventTime,
+		doc.Data.PricePerMonth, doc.Data.Status, doc.Data.ErrorMessage, data)
+
+	if err != nil {
+		log.WithFields(log.Fields{
+			"execution_id": doc.ExecutionID,
+			"data":         data,
+		}).WithError(err).Error("Fail to save document")
+		return false
+	}
+
+	return true
+}
+
+// GetSummary returns executions summary
+func (sm *StorageManager) GetSummary(executionID string, filters map[string]string) (map[string]storage.CollectorsSummary, error) {
+	summary := map[string]storage.CollectorsSummary{}
+
+	rows, err := sm.db.Query(`
+		SELECT resource_name, event_time, status, error_message
+		FROM resource_events
+		WHERE execution_id = $1 AND event_type = 'service_status'`, executionID)
+
+	if err != nil {
+		log.WithError(err).Error("error when trying to get summary data")
+		return summary, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var resourceName, status, errorMessage string
+		var eventTime int64
+
+		if err := rows.Scan(&resourceName, &eventTime, &status, &errorMessage); err != nil {
+			log.WithError(err).Error("could not parse summary row")
+			continue
+		}
+
+		val, found := summary[resourceName]
+		if found && eventTime < val.EventTime {
+			continue
+		}
+
+		summary[resourceName] = storage.CollectorsSummary{
+			EventTime:    eventTime,
+			Status:       status,
+			ResourceName: resourceName,
+			ErrorMessage: errorMessage,
+		}
+	}
+
+	for resourceName, resourceData := range summary {
+		filters["ResourceName"] = resourceName
+		totalSpent, resourceCount, err := sm.getResourceSummaryDetails(executionID, filters)
+		if err != nil {
+			continue
+		}
+
+		newResourceData := resourceData
+		newResourceData.TotalSpent = totalSpent
+		newResourceData.ResourceCount = resourceCount
+		summary[resourceName] = newResourceData
+	}
+
+	return summary, nil
+}
+
+// filterColumns maps a caller-supplied filter name to the resource_events column it
+// corresponds to. filters comes straight from the caller (HTTP query params once wired
+// to the API), so only names present in this allow-list are ever interpolated into SQL
+// text; anything else is ignored rather than spliced into the query.
+var filterColumns = map[string]string{
+	"ResourceName": "resource_name",
+}
+
+// getResourceSummaryDetails returns total resource spent and total resources detected
+func (sm *StorageManager) getResourceSummaryDetails(executionID string, filters map[string]string) (float64, int64, error) {
+
+	var totalSpent float64
+	var resourceCount int64
+
+	query := strings.Builder{}
+	query.WriteString(`
+		SELECT COALESCE(SUM(price_per_month), 0), COUNT(*)
+		FROM resource_events
+		WHERE execution_id = $1 AND event_type = 'resource_detected'`)
+
+	args := []interface{}{executionID}
+	for name, value := range filters {
+		column, ok := filterColumns[name]
+		if !ok {
+			log.WithField("filter", name).Warn("ignoring unknown filter")
+			continue
+		}
+		args = append(args, value)
+		query.WriteString(" AND " + column + " = $" + strconv.Itoa(len(args)))
+	}
+
+	row := sm.db.QueryRow(query.String(), args...)
+	if err := row.Scan(&totalSpent, &resourceCount); err != nil {
+		log.WithError(err).WithField("filters", filters).Error("error when trying to get summary details")
+		return totalSpent, resourceCount, err
+	}
+
+	return totalSpent, resourceCount, nil
+}
+
+// defaultPageLimit is used for GetExecutions/GetResources when page.Limit is not set
+const defaultPageLimit = 100
+
+// GetExecutions returns collector executions, keyset-paginated on (last_event_time,
+// execution_id) via page.After instead of a fixed LIMIT.
+func (sm *StorageManager) GetExecutions(page storage.PageRequest) (storage.ExecutionsPage, error) {
+	result := storage.ExecutionsPage{Executions: []storage.Executions{}}
+
+	limit := page.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+
+	query := strings.Builder{}
+	query.WriteString(`
+		SELECT execution_id, last_event_time FROM (
+			SELECT execution_id, MAX(event_time) AS last_event_time
+			FROM resource_events
+			WHERE event_type = 'service_status'
+			GROUP BY execution_id
+		) executions`)
+
+	args := []interface{}{limit}
+	if page.After != "" {
+		afterEventTime, afterExecutionID, err := decodeExecutionsAfter(page.After)
+		if err != nil {
+			log.WithError(err).WithField("after", page.After).Error("could not decode executions page cursor")
+		} else {
+			args = append(args, afterEventTime, afterExecutionID)
+			query.WriteString(" WHERE (last_event_time, execution_id) < ($2, $3)")
+		}
+	}
+	query.WriteString(" ORDER BY last_event_time DESC, execution_id DESC LIMIT $1")
+
+	rows, err := sm.db.Query(query.String(), args...)
+	if err != nil {
+		log.WithError(err).Error("error when trying to get executions collectors")
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var executionID string
+		var lastEventTime int64
+
+		if err := rows.Scan(&executionID, &lastEventTime); err != nil {
+			log.WithError(err).Error("error when trying to parse execution row")
+			continue
+		}
+
+		data := strings.Split(executionID, "_")
+
+		// Remove the last element of Data which is the timestamp and leave all the others elements
+		// Which construct the executionName
+		executionName := strings.Join(data[:len(data)-1], "_")
+
+		result.Executions = append(result.Executions, storage.Executions{
+			ID:   executionID,
+			Name: executionName,
+			Time: time.Unix(lastEventTime, 0),
+		})
+	}
+
+	if len(result.Executions) == limit {
+		last := result.Executions[len(result.Executions)-1]
+		result.After = encodeExecutionsAfter(last.Time.Unix(), last.ID)
+	}
+
+	return result, nil
+}
+
+func encodeExecutionsAfter(eventTime int64, executionID string) string {
+	return fmt.Sprintf("%d|%s", eventTime, executionID)
+}
+
+func decodeExecutionsAfter(after string) (int64, string, error) {
+	parts := strings.SplitN(after, "|", 2)
+	if len(parts) != 2 {
+		return 0, "", fmt.Errorf("malformed page cursor %q", after)
+	}
+
+	eventTime, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("malformed page cursor %q: %w", after, err)
+	}
+
+	return eventTime, parts[1], nil
+}
+
+// GetResources return resource data, keyset-paginated on the row id via page.After
+func (sm *StorageManager) GetResources(resourceType string, executionID string, page storage.PageRequest) (storage.ResourcesPage, error) {
+
+	result := storage.ResourcesPage{}
+
+	limit := page.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+
+	query := strings.Builder{}
+	query.WriteString(`
+		SELECT id, data
+		FROM resource_events
+		WHERE event_type = 'resource_detected' AND execution_id = $1 AND resource_name = $2`)
+
+	args := []interface{}{executionID, resourceType}
+	if page.After != "" {
+		afterID, err := strconv.ParseInt(page.After, 10, 64)
+		if err != nil {
+			log.WithError(err).WithField("after", page.After).Error("could not decode resources page cursor")
+		} else {
+			args = append(args, afterID)
+			query.WriteString(fmt.Sprintf(" AND id > $%d", len(args)))
+		}
+	}
+	args = append(args, limit)
+	query.WriteString(fmt.Sprintf(" ORDER BY id ASC LIMIT $%d", len(args)))
+
+	rows, err := sm.db.Query(query.String(), args...)
+	if err != nil {
+		log.WithError(err).Error("postgres query error")
+		return result, err
+	}
+	defer rows.Close()
+
+	var lastID int64
+	for rows.Next() {
+		var rawData string
+		if err := rows.Scan(&lastID, &rawData); err != nil {
+			log.WithError(err).Error("error when trying to parse result row")
+			continue
+		}
+
+		rowData := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(rawData), &rowData); err != nil {
+			log.WithError(err).Error("error when trying to parse search result hits data")
+			continue
+		}
+
+		result.Resources = append(result.Resources, rowData)
+	}
+
+	if len(result.Resources) == limit {
+		result.After = strconv.FormatInt(lastID, 10)
+	}
+
+	return result, nil
+}
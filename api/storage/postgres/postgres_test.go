@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// stubDriver is a minimal database/sql/driver.Driver that records the query text and args
+// passed through it, so getResourceSummaryDetails can be tested without a real Postgres
+// connection.
+type stubDriver struct {
+	gotQuery string
+	gotArgs  []driver.Value
+}
+
+func (d *stubDriver) Open(name string) (driver.Conn, error) {
+	return &stubConn{driver: d}, nil
+}
+
+type stubConn struct {
+	driver *stubDriver
+}
+
+func (c *stubConn) Prepare(query string) (driver.Stmt, error) {
+	c.driver.gotQuery = query
+	return &stubStmt{driver: c.driver}, nil
+}
+func (c *stubConn) Close() error              { return nil }
+func (c *stubConn) Begin() (driver.Tx, error) { return nil, errors.New("not implemented") }
+
+type stubStmt struct {
+	driver *stubDriver
+}
+
+func (s *stubStmt) Close() error  { return nil }
+func (s *stubStmt) NumInput() int { return -1 }
+func (s *stubStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not implemented")
+}
+func (s *stubStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.driver.gotArgs = args
+	return &stubRows{}, nil
+}
+
+// stubRows yields a single (totalSpent, resourceCount) row
+type stubRows struct {
+	done bool
+}
+
+func (r *stubRows) Columns() []string { return []string{"sum", "count"} }
+func (r *stubRows) Close() error      { return nil }
+func (r *stubRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = float64(12.5)
+	dest[1] = int64(3)
+	return nil
+}
+
+func newStubManager(t *testing.T) (*StorageManager, *stubDriver) {
+	t.Helper()
+
+	d := &stubDriver{}
+	name := "postgres-stub-" + t.Name()
+	sql.Register(name, d)
+
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("could not open stub db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return &StorageManager{db: db}, d
+}
+
+// TestGetResourceSummaryDetailsFilterAllowList asserts that only filter names present in
+// filterColumns are ever interpolated into the query text, and that unknown names are
+// dropped instead of being spliced in. This is the exact code path that had a SQL
+// injection bug fixed in a previous change.
+func TestGetResourceSummaryDetailsFilterAllowList(t *testing.T) {
+	tests := []struct {
+		name          string
+		filters       map[string]string
+		wantCondition string
+		wantAbsent    []string
+	}{
+		{
+			name:          "known filter is applied",
+			filters:       map[string]string{"ResourceName": "ec2"},
+			wantCondition: "AND resource_name = $2",
+		},
+		{
+			name:    "unknown filter is dropped, not interpolated",
+			filters: map[string]string{"foo' = 'x' OR '1'='1": "ec2"},
+			wantAbsent: []string{
+				"foo' = 'x' OR '1'='1",
+			},
+		},
+		{
+			name:    "sql injection attempt via filter name is not spliced into the query",
+			filters: map[string]string{"1) OR (1=1) --": "ec2"},
+			wantAbsent: []string{
+				"1) OR (1=1) --",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm, d := newStubManager(t)
+
+			if _, _, err := sm.getResourceSummaryDetails("exec-1", tt.filters); err != nil {
+				t.Fatalf("getResourceSummaryDetails returned an error: %v", err)
+			}
+
+			if tt.wantCondition != "" && !strings.Contains(d.gotQuery, tt.wantCondition) {
+				t.Errorf("query %q does not contain expected condition %q", d.gotQuery, tt.wantCondition)
+			}
+
+			for _, absent := range tt.wantAbsent {
+				if strings.Contains(d.gotQuery, absent) {
+					t.Errorf("query %q unexpectedly contains untrusted filter name %q", d.gotQuery, absent)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeDecodeExecutionsAfter(t *testing.T) {
+	tests := []struct {
+		name        string
+		eventTime   int64
+		executionID string
+	}{
+		{name: "round trip", eventTime: 1700000000, executionID: "ec2-collector_1700000000"},
+		{name: "empty execution id", eventTime: 42, executionID: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := encodeExecutionsAfter(tt.eventTime, tt.executionID)
+
+			gotEventTime, gotExecutionID, err := decodeExecutionsAfter(cursor)
+			if err != nil {
+				t.Fatalf("decodeExecutionsAfter(%q) returned an error: %v", cursor, err)
+			}
+			if gotEventTime != tt.eventTime || gotExecutionID != tt.executionID {
+				t.Errorf("decodeExecutionsAfter(%q) = (%d, %q), want (%d, %q)",
+					cursor, gotEventTime, gotExecutionID, tt.eventTime, tt.executionID)
+			}
+		})
+	}
+}
+
+func TestDecodeExecutionsAfterMalformed(t *testing.T) {
+	malformed := []string{
+		"",
+		"no-separator",
+		"not-a-number|exec-1",
+	}
+
+	for _, cursor := range malformed {
+		if _, _, err := decodeExecutionsAfter(cursor); err == nil {
+			t.Errorf("decodeExecutionsAfter(%q) should have returned an error", cursor)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrStorageUnavailable is returned by Storage implementations when the underlying
+// backend cannot currently serve requests
+var ErrStorageUnavailable = errors.New("storage backend is currently unavailable")
+
+// Storage is implemented by every supported storage backend (Elasticsearch and Postgres;
+// there is no SQLite implementation) so the API layer can be wired up without depending
+// on a specific one
+type Storage interface {
+	// Save enqueues a document for storage
+	Save(data string) bool
+	// GetSummary returns executions summary
+	GetSummary(executionID string, filters map[string]string) (map[string]CollectorsSummary, error)
+	// GetResources return resource data
+	GetResources(resourceType string, executionID string, page PageRequest) (ResourcesPage, error)
+	// GetExecutions returns collector executions
+	GetExecutions(page PageRequest) (ExecutionsPage, error)
+	// Close releases any resources held by the backend, draining in-flight work until ctx
+	// is done
+	Close(ctx context.Context) error
+}
+
+// PageRequest describes the pagination input accepted by paginated Storage queries. An
+// empty After starts from the beginning; a non-positive Limit falls back to the
+// implementation's default page size.
+type PageRequest struct {
+	After string
+	Limit int
+}
+
+// ExecutionsPage is the paginated result of GetExecutions. After is empty once there are
+// no more executions to page through.
+type ExecutionsPage struct {
+	Executions []Executions
+	After      string
+}
+
+// ResourcesPage is the paginated result of GetResources. After is empty once there are no
+// more resources to page through.
+type ResourcesPage struct {
+	Resources []map[string]interface{}
+	After     string
+}
+
+// SummaryData describes the collector status payload stored alongside a summary event
+type SummaryData struct {
+	Status       string `json:"Status"`
+	ErrorMessage string `json:"ErrorMessage"`
+}
+
+// Summary is the raw document shape used to aggregate collector summaries
+type Summary struct {
+	ResourceName string      `json:"ResourceName"`
+	EventTime    int64       `json:"EventTime"`
+	Data         SummaryData `json:"Data"`
+}
+
+// CollectorsSummary describes the status and cost of a single collector's run
+type CollectorsSummary struct {
+	ResourceName  string  `json:"ResourceName"`
+	EventTime     int64   `json:"EventTime"`
+	Status        string  `json:"Status"`
+	ErrorMessage  string  `json:"ErrorMessage"`
+	TotalSpent    float64 `json:"TotalSpent"`
+	ResourceCount int64   `json:"ResourceCount"`
+}
+
+// Executions describes a single collector execution
+type Executions struct {
+	ID   string    `json:"ID"`
+	Name string    `json:"Name"`
+	Time time.Time `json:"Time"`
+}
@@ -0,0 +1,82 @@
+//go:build integration
+
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"finala/api/config"
+	"finala/api/storage"
+	"finala/api/storage/elasticsearch"
+	"finala/api/storage/elasticsearchv8"
+)
+
+// newStorageManager constructs a storage.Storage backed by a specific Elasticsearch client
+// version
+type newStorageManager func(config.ElasticsearchConfig) (storage.Storage, error)
+
+// TestStorageManagers runs the same save/query scenario against both supported
+// Elasticsearch client versions. It requires a cluster reachable at
+// FINALA_TEST_ES_ENDPOINT (defaults to http://localhost:9200) and only runs when built
+// with the "integration" build tag.
+func TestStorageManagers(t *testing.T) {
+	endpoint := os.Getenv("FINALA_TEST_ES_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "http://localhost:9200"
+	}
+
+	backends := map[string]newStorageManager{
+		"v7": func(conf config.ElasticsearchConfig) (storage.Storage, error) {
+			return elasticsearch.NewStorageManager(conf)
+		},
+		"v8": func(conf config.ElasticsearchConfig) (storage.Storage, error) {
+			return elasticsearchv8.NewStorageManager(conf)
+		},
+	}
+
+	for name, newSM := range backends {
+		name, newSM := name, newSM
+		t.Run(name, func(t *testing.T) {
+			conf := config.ElasticsearchConfig{
+				Endpoints: []string{endpoint},
+				Index:     fmt.Sprintf("finala-storage-test-%s", name),
+			}
+
+			sm, err := newSM(conf)
+			if err != nil {
+				t.Fatalf("could not create storage manager: %v", err)
+			}
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				if err := sm.Close(ctx); err != nil {
+					t.Errorf("Close returned an error: %v", err)
+				}
+			}()
+
+			executionID := fmt.Sprintf("test-collector_%d", time.Now().Unix())
+			doc := fmt.Sprintf(`{"ExecutionID":%q,"EventType":"service_status","ResourceName":"test-resource","EventTime":%d,"Data":{"Status":"success"}}`,
+				executionID, time.Now().Unix())
+
+			if !sm.Save(doc) {
+				t.Fatal("expected Save to succeed")
+			}
+
+			if _, err := sm.GetExecutions(storage.PageRequest{}); err != nil {
+				t.Fatalf("GetExecutions returned an error: %v", err)
+			}
+
+			if _, err := sm.GetSummary(executionID, map[string]string{}); err != nil {
+				t.Fatalf("GetSummary returned an error: %v", err)
+			}
+
+			if _, err := sm.GetResources("test-resource", executionID, storage.PageRequest{}); err != nil {
+				t.Fatalf("GetResources returned an error: %v", err)
+			}
+		})
+	}
+}